@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/weregoat/mmdb_intervals/pkg/interval"
+	"github.com/weregoat/mmdb_intervals/pkg/nftsync"
+)
+
+// Emitter turns a set of coalesced intervals into some external
+// representation, so the tool isn't limited to nftables.
+type Emitter interface {
+	Emit(intervals []*interval.Interval) error
+}
+
+// NewEmitter builds the Emitter selected by -out. Text-based formats are
+// written to w; the nft format talks to nftables directly and ignores w.
+func NewEmitter(kind string, w io.Writer, tableName, setName, country string, dryRun, prune bool) (Emitter, error) {
+	if setName == "" {
+		setName = "geoip"
+	}
+	switch kind {
+	case "nft":
+		return &nftEmitter{table: tableName, set: setName, dryRun: dryRun, prune: prune}, nil
+	case "ipset":
+		return &ipsetEmitter{w: w, set: setName}, nil
+	case "iptables":
+		return &iptablesEmitter{w: w, set: setName}, nil
+	case "bird":
+		return &birdEmitter{w: w, listName: setName}, nil
+	case "json":
+		return &jsonEmitter{w: w, country: country}, nil
+	default:
+		return nil, fmt.Errorf("unknown -out %q, must be one of nft, ipset, iptables, bird, json", kind)
+	}
+}
+
+// nftEmitter reconciles intervals against an existing nftables interval
+// set instead of blindly re-adding them.
+type nftEmitter struct {
+	table  string
+	set    string
+	dryRun bool
+	prune  bool
+}
+
+func (e *nftEmitter) Emit(intervals []*interval.Interval) error {
+	return nftsync.Reconcile(e.table, e.set, intervals, e.dryRun, e.prune, dLog)
+}
+
+// ipsetEmitter writes an `ipset restore` script that creates a hash:net
+// set and populates it with the CIDR blocks for each interval. IPv4 and
+// IPv6 intervals are written to separate sets (set and set+"6"), since a
+// single hash:net set can only hold one family.
+type ipsetEmitter struct {
+	w   io.Writer
+	set string
+}
+
+func (e *ipsetEmitter) Emit(intervals []*interval.Interval) error {
+	e.emitFamily(interval.FamilyV4, "inet", e.set, intervals)
+	e.emitFamily(interval.FamilyV6, "inet6", e.set+"6", intervals)
+	return nil
+}
+
+// emitFamily writes the create/add block for the intervals of a single
+// family, using setName so IPv4 and IPv6 can be split into two ipset sets
+// instead of mixing both families under one `hash:net family` header.
+func (e *ipsetEmitter) emitFamily(family interval.Family, ipsetFamily, setName string, intervals []*interval.Interval) {
+	var matching []*interval.Interval
+	for _, i := range intervals {
+		if i.Family() == family {
+			matching = append(matching, i)
+		}
+	}
+	if len(matching) == 0 {
+		return
+	}
+	fmt.Fprintf(e.w, "create %s hash:net family %s\n", setName, ipsetFamily)
+	for _, i := range matching {
+		for _, cidr := range i.CIDRs() {
+			fmt.Fprintf(e.w, "add %s %s\n", setName, cidr.String())
+		}
+	}
+}
+
+// iptablesEmitter writes an iptables-save fragment dropping traffic from
+// the interval's CIDR blocks.
+type iptablesEmitter struct {
+	w   io.Writer
+	set string
+}
+
+func (e *iptablesEmitter) Emit(intervals []*interval.Interval) error {
+	for _, i := range intervals {
+		for _, cidr := range i.CIDRs() {
+			fmt.Fprintf(e.w, "-A %s -s %s -j DROP\n", e.set, cidr.String())
+		}
+	}
+	return nil
+}
+
+// birdEmitter writes a BIRD route filter list of the interval's CIDR
+// blocks.
+type birdEmitter struct {
+	w        io.Writer
+	listName string
+}
+
+func (e *birdEmitter) Emit(intervals []*interval.Interval) error {
+	var blocks []string
+	for _, i := range intervals {
+		for _, cidr := range i.CIDRs() {
+			blocks = append(blocks, cidr.String())
+		}
+	}
+	fmt.Fprintf(e.w, "define %s = [\n", e.listName)
+	for i, block := range blocks {
+		sep := ","
+		if i == len(blocks)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(e.w, "\t%s%s\n", block, sep)
+	}
+	fmt.Fprintln(e.w, "];")
+	return nil
+}
+
+// jsonEmitter writes the coalesced ranges as a single JSON document.
+type jsonEmitter struct {
+	w       io.Writer
+	country string
+}
+
+type jsonRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type jsonDocument struct {
+	Country string      `json:"country"`
+	Ranges  []jsonRange `json:"ranges"`
+}
+
+func (e *jsonEmitter) Emit(intervals []*interval.Interval) error {
+	doc := jsonDocument{Country: e.country}
+	for _, i := range intervals {
+		doc.Ranges = append(doc.Ranges, jsonRange{
+			Start: i.Lower().String(),
+			End:   i.Upper().String(),
+		})
+	}
+	enc := json.NewEncoder(e.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/weregoat/mmdb_intervals/pkg/interval"
+)
+
+func mixedFamilyIntervals() []*interval.Interval {
+	return []*interval.Interval{
+		interval.NewInterval("198.51.100.0/24"),
+		interval.NewInterval("2001:db8::/32"),
+	}
+}
+
+func TestIpsetEmitterSplitsByFamily(t *testing.T) {
+	var buf bytes.Buffer
+	e := &ipsetEmitter{w: &buf, set: "geoip"}
+	if err := e.Emit(mixedFamilyIntervals()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "create geoip hash:net family inet\n" +
+		"add geoip 198.51.100.0/24\n" +
+		"create geoip6 hash:net family inet6\n" +
+		"add geoip6 2001:db8::/32\n"
+	if buf.String() != expected {
+		t.Errorf("expecting:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestIpsetEmitterSkipsEmptyFamily(t *testing.T) {
+	var buf bytes.Buffer
+	e := &ipsetEmitter{w: &buf, set: "geoip"}
+	if err := e.Emit([]*interval.Interval{interval.NewInterval("198.51.100.0/24")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "create geoip hash:net family inet\n" +
+		"add geoip 198.51.100.0/24\n"
+	if buf.String() != expected {
+		t.Errorf("expecting:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestIptablesEmitter(t *testing.T) {
+	var buf bytes.Buffer
+	e := &iptablesEmitter{w: &buf, set: "GEOIP"}
+	if err := e.Emit(mixedFamilyIntervals()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "-A GEOIP -s 198.51.100.0/24 -j DROP\n" +
+		"-A GEOIP -s 2001:db8::/32 -j DROP\n"
+	if buf.String() != expected {
+		t.Errorf("expecting:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestBirdEmitter(t *testing.T) {
+	var buf bytes.Buffer
+	e := &birdEmitter{w: &buf, listName: "geoip"}
+	if err := e.Emit(mixedFamilyIntervals()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "define geoip = [\n" +
+		"\t198.51.100.0/24,\n" +
+		"\t2001:db8::/32\n" +
+		"];\n"
+	if buf.String() != expected {
+		t.Errorf("expecting:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestJsonEmitter(t *testing.T) {
+	var buf bytes.Buffer
+	e := &jsonEmitter{w: &buf, country: "US"}
+	intervals := []*interval.Interval{interval.NewInterval("198.51.100.0/24")}
+	if err := e.Emit(intervals); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "{\n" +
+		"  \"country\": \"US\",\n" +
+		"  \"ranges\": [\n" +
+		"    {\n" +
+		"      \"start\": \"198.51.100.0\",\n" +
+		"      \"end\": \"198.51.101.0\"\n" +
+		"    }\n" +
+		"  ]\n" +
+		"}\n"
+	if buf.String() != expected {
+		t.Errorf("expecting:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"io"
+	"log"
+
+	"os"
+	"strings"
+
+	"github.com/weregoat/mmdb_intervals/pkg/interval"
+	"github.com/weregoat/mmdb_intervals/pkg/mmdbscan"
+)
+
+var debug bool
+
+func main() {
+
+	flag.CommandLine.SetOutput(os.Stdout)
+	file := flag.String("db", "", "MaxmindDB file with the IP ranges for countries")
+	list := flag.Bool("print", false, "Prints resulting networks")
+	setName := flag.String("set", "", "Add networks to nftables set")
+	tableName := flag.String("table", "filter", "Name of the nftable the set is in")
+	family := flag.String("family", "v4", "Address family to process: v4, v6 or both")
+	cidr := flag.Bool("cidr", false, "With -print, print the minimal list of CIDR blocks instead of ranges")
+	outKind := flag.String("out", "nft", "Output backend: nft, ipset, iptables, bird or json")
+	outFile := flag.String("o", "", "File to write -out output to (defaults to stdout, ignored for -out nft)")
+	dryRun := flag.Bool("dry-run", false, "With -out nft, print the diff against the existing set instead of applying it")
+	prune := flag.Bool("prune", false, "With -out nft, remove elements from the existing set that are no longer wanted")
+	selectExpr := flag.String("select", "", "Boolean selector expression, e.g. 'country=DE OR asn=15169 AND NOT country=US' (overrides positional country codes)")
+	flag.BoolVar(&debug, "debug", false, "Print debug logs (very verbose)")
+	flag.Usage = usage
+	flag.Parse()
+
+	countries := flag.Args()
+	if len(countries) == 0 && *selectExpr == "" {
+		check(errors.New("need to specify at least one ISO country code or a -select expression"))
+	}
+	selector, err := buildSelector(*selectExpr, countries)
+	check(err)
+	families, err := parseFamilies(*family)
+	check(err)
+	db, err := maxminddb.Open(*file)
+	check(err)
+	defer db.Close()
+
+	var intervals []*interval.Interval
+	err = mmdbscan.Scan(db, selector, families, func(n *interval.Interval) {
+		dLog(fmt.Sprintf("subnet %s matched by selector", n.String()))
+		intervals = append(intervals, n)
+	})
+	check(err)
+
+	intervals = interval.Coalesce(intervals)
+
+	if *outKind == "nft" {
+		if len(*setName) > 0 && len(*tableName) > 0 {
+			emitter, err := NewEmitter(*outKind, nil, *tableName, *setName, "", *dryRun, *prune)
+			check(err)
+			check(emitter.Emit(intervals))
+		}
+	} else {
+		out := io.Writer(os.Stdout)
+		if len(*outFile) > 0 {
+			f, err := os.Create(*outFile)
+			check(err)
+			defer f.Close()
+			out = f
+		}
+		emitter, err := NewEmitter(*outKind, out, *tableName, *setName, strings.Join(countries, ","), false, false)
+		check(err)
+		check(emitter.Emit(intervals))
+	}
+	if *list {
+		if *cidr {
+			printCIDRs(intervals)
+		} else {
+			print(intervals)
+		}
+	}
+
+}
+
+func check(e error) {
+	if e != nil {
+		log.Fatal(e)
+	}
+}
+
+func dLog(message string) {
+	if debug {
+		log.Print(message)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "%s [-print] [-cidr] [-out nft|ipset|iptables|bird|json] [-o file] [-set nft_set] [-table nft_table] [-family v4|v6|both] [-dry-run] [-prune] [-select expression] {-db option_argument} [country_code...]\n", os.Args[0])
+	fmt.Fprintln(flag.CommandLine.Output(), "Reads the network intervals for the countries specified as ISO 3166-1 alpha2 code from a MaxmindDB GeoIP2 database")
+	flag.PrintDefaults()
+}
+
+// parseFamilies turns the -family flag value into the set of families to
+// process.
+func parseFamilies(value string) (map[interval.Family]bool, error) {
+	switch value {
+	case "v4":
+		return map[interval.Family]bool{interval.FamilyV4: true}, nil
+	case "v6":
+		return map[interval.Family]bool{interval.FamilyV6: true}, nil
+	case "both":
+		return map[interval.Family]bool{interval.FamilyV4: true, interval.FamilyV6: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown -family %q, must be v4, v6 or both", value)
+	}
+}
+
+// buildSelector returns the Selector for -select, or - when that flag is
+// empty - the legacy behaviour of ORing together the positional country
+// codes.
+func buildSelector(expr string, countries []string) (mmdbscan.Selector, error) {
+	if expr != "" {
+		return mmdbscan.ParseSelector(expr)
+	}
+	return mmdbscan.FromCountries(countries), nil
+}
+
+func print(intervals []*interval.Interval) {
+	for _, i := range intervals {
+		fmt.Println(i.String())
+	}
+}
+
+func printCIDRs(intervals []*interval.Interval) {
+	for _, i := range intervals {
+		for _, cidr := range i.CIDRs() {
+			fmt.Println(cidr.String())
+		}
+	}
+}
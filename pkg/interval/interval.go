@@ -0,0 +1,414 @@
+// Package interval implements IP address intervals: building one from a
+// CIDR, merging overlapping or adjacent ones, and decomposing a merged
+// interval back into a minimal list of CIDR blocks.
+package interval
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// Family distinguishes IPv4 from IPv6 addresses. Both are stored as a
+// big.Int internally so the rest of the package does not need two code
+// paths for interval arithmetic.
+type Family int
+
+const (
+	FamilyV4 Family = iota
+	FamilyV6
+)
+
+const (
+	ipv4BitLen = 32
+	ipv6BitLen = 128
+)
+
+func (f Family) bitLen() int {
+	if f == FamilyV6 {
+		return ipv6BitLen
+	}
+	return ipv4BitLen
+}
+
+func (f Family) byteLen() int {
+	return f.bitLen() / 8
+}
+
+func (f Family) String() string {
+	if f == FamilyV6 {
+		return "v6"
+	}
+	return "v4"
+}
+
+// Address is an IPv4 or IPv6 address held as a big.Int, which is what lets
+// NewInterval, broadcast, Next, CanJoin and Join share a single
+// implementation for both families instead of one per address size.
+type Address struct {
+	IntValue  *big.Int
+	IPAddress []byte // Not strictly necessary, but handy to keep around
+	Family    Family
+}
+
+// The general problem with using CIDR and masks for IP networks intervals,
+// is that they don't always match and you might end up with multiple CIDR
+// for describing a single interval.
+// This is, exactly, what is happening with the GeoIP databases. Where you
+// get multiple /22 subnets, for example, to describe a single interval.
+// That's fine for lookups, but what I want is to reduce the number of elements
+// in the set.
+// Also, as far as I understand it, intervals are the way subnets are entered in nftables.
+// Lower address is included, upper is not [,).
+type Interval struct {
+	lower *Address
+	upper *Address
+}
+
+func (n *Interval) Lower() net.IP {
+	return n.lower.ToIP()
+}
+
+func (n *Interval) Upper() net.IP {
+	return n.upper.ToIP()
+}
+
+// Family reports whether the interval is made of IPv4 or IPv6 addresses.
+func (n *Interval) Family() Family {
+	return n.lower.Family
+}
+
+// FromBounds builds an Interval directly from its lower (included) and
+// upper (excluded) bounds, for callers that already have Addresses, such
+// as one rebuilding intervals from an existing nftables set.
+func FromBounds(lower, upper *Address) *Interval {
+	return &Interval{lower: lower, upper: upper}
+}
+
+// New Interval initialise a network interval from a CIDR string.
+func NewInterval(CIDR string) *Interval {
+	ip, subnet, err := net.ParseCIDR(CIDR)
+	if err != nil {
+		return nil
+	}
+	if ip == nil || isZeros(ip) {
+		return nil
+	}
+	network := NewAddress(ip)
+	if network == nil {
+		return nil
+	}
+	if !network.Valid() {
+		return nil
+	}
+	// A single host route (/32 for IPv4, /128 for IPv6) has no room for an
+	// interval, so it is skipped just like before.
+	ones, bits := subnet.Mask.Size()
+	if bits == 0 || ones == bits {
+		return nil
+	}
+	broadcastAddress := broadcast(*subnet, network.Family)
+	if !broadcastAddress.Valid() {
+		return nil
+	}
+	nextAddress := broadcastAddress.Next()
+	if !nextAddress.Valid() {
+		return nil
+	}
+	n := &Interval{}
+	n.lower = network
+	n.upper = nextAddress
+	return n
+}
+
+// Valid evaluate an address according to various criteria that make it
+// not suitable to be used in an interval. Not necessarily a bad IP address.
+func (a Address) Valid() bool {
+	if a.IntValue == nil {
+		return false
+	}
+	if len(a.IPAddress) != a.Family.byteLen() {
+		return false
+	}
+	if isZeros(a.IPAddress) {
+		return false
+	}
+	if a.IntValue.Sign() == 0 {
+		return false
+	}
+	return true
+}
+
+func (a Address) String() string {
+	return a.ToIP().String()
+}
+
+// NewAddress builds an Address from a net.IP, picking IPv4 or IPv6
+// representation depending on what the address actually is.
+func NewAddress(ip net.IP) *Address {
+	family, raw := addressBytes(ip)
+	if raw == nil {
+		return nil
+	}
+	return &Address{
+		IPAddress: raw,
+		IntValue:  new(big.Int).SetBytes(raw),
+		Family:    family,
+	}
+}
+
+// addressBytes returns the family-appropriate byte slice for ip, or a nil
+// slice if ip isn't a valid address of either family.
+func addressBytes(ip net.IP) (Family, []byte) {
+	if v4 := ip.To4(); v4 != nil {
+		return FamilyV4, v4
+	}
+	if v6 := ip.To16(); v6 != nil {
+		return FamilyV6, v6
+	}
+	return FamilyV4, nil
+}
+
+// AddressFromBytes rebuilds an Address from a raw 4- or 16-byte key, such
+// as one read back from an nftables set element.
+func AddressFromBytes(raw []byte) *Address {
+	family := FamilyV4
+	if len(raw) == net.IPv6len {
+		family = FamilyV6
+	}
+	return addressFromInt(new(big.Int).SetBytes(raw), family)
+}
+
+// addressFromInt rebuilds an Address from a big.Int for the given family,
+// used once arithmetic (broadcast, Next, Join...) has produced a new value.
+func addressFromInt(value *big.Int, family Family) *Address {
+	raw := value.Bytes()
+	if len(raw) > family.byteLen() {
+		return nil
+	}
+	buf := make([]byte, family.byteLen())
+	copy(buf[len(buf)-len(raw):], raw)
+	return &Address{
+		IntValue:  value,
+		IPAddress: buf,
+		Family:    family,
+	}
+}
+
+func hostBits(mask net.IPMask) int {
+	ones, bits := mask.Size()
+	return bits - ones
+}
+
+func broadcast(subNet net.IPNet, family Family) *Address {
+	base := new(big.Int).SetBytes(subNet.IP)
+	host := hostBits(subNet.Mask)
+	hostMask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(host)), big.NewInt(1))
+	value := new(big.Int).Or(base, hostMask)
+	return addressFromInt(value, family)
+}
+
+// Next returns the address immediately following a, wrapping around to the
+// zero address (which fails Valid) if a is the last address of its family.
+func (a Address) Next() *Address {
+	if !a.Valid() {
+		return nil
+	}
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(a.Family.bitLen()))
+	value := new(big.Int).Add(a.IntValue, big.NewInt(1))
+	value.Mod(value, modulus)
+	return addressFromInt(value, a.Family)
+}
+
+func (a Address) ToIP() net.IP {
+	if a.Family == FamilyV6 {
+		return net.IP(a.IPAddress)
+	}
+	return net.IPv4(
+		a.IPAddress[0],
+		a.IPAddress[1],
+		a.IPAddress[2],
+		a.IPAddress[3],
+	)
+}
+
+/*
+func (n *Interval) Contains(ip net.IP) bool {
+
+	for _,subNet := range n.subNets {
+		if subNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+*/
+
+func CanJoin(a *Interval, b *Interval) bool {
+	if a.Family() != b.Family() {
+		return false
+	}
+	aLower := a.lower.IntValue
+	bLower := b.lower.IntValue
+	aUpper := a.upper.IntValue
+	bUpper := b.upper.IntValue
+	// a overlaps lower end of b
+	if aLower.Cmp(bLower) <= 0 && aUpper.Cmp(bLower) >= 0 {
+		return true
+	}
+	// a overlaps upper end of b
+	if aLower.Cmp(bUpper) <= 0 && aLower.Cmp(bLower) >= 0 {
+		return true
+	}
+	return false
+}
+
+func Join(a *Interval, b *Interval) *Interval {
+	if !CanJoin(a, b) {
+		return nil
+	}
+	n := &Interval{}
+	n.lower = min(
+		a.lower,
+		b.lower,
+	)
+	n.upper = max(
+		a.upper,
+		b.upper,
+	)
+	return n
+}
+
+func min(a *Address, b *Address) *Address {
+	if a.IntValue.Cmp(b.IntValue) <= 0 {
+		return a
+	}
+	return b
+}
+
+func max(a *Address, b *Address) *Address {
+	if a.IntValue.Cmp(b.IntValue) >= 0 {
+		return a
+	}
+	return b
+}
+
+/*
+func mergeSubNets(a []net.IPNet, b []net.IPNet) []net.IPNet {
+	var subNets = make([]net.IPNet, len(a))
+	copy(subNets, a)
+	for _,i := range b {
+		dup := false
+		for _,j := range a {
+			// Shortcut
+			if i.String() == j.String() {
+				dup = true
+				break
+			}
+		}
+		if ! dup {
+			subNets = append(subNets, i)
+		}
+	}
+	return subNets
+}
+*/
+
+// CIDRs decomposes the interval back into the minimum set of aligned CIDR
+// blocks covering [lower, upper). At each step it takes the largest prefix
+// length whose block both starts at lower and fits under upper, emits it,
+// and advances lower past it.
+func (n *Interval) CIDRs() []*net.IPNet {
+	family := n.Family()
+	bitLen := family.bitLen()
+	lower := new(big.Int).Set(n.lower.IntValue)
+	upper := n.upper.IntValue
+
+	var blocks []*net.IPNet
+	for lower.Cmp(upper) < 0 {
+		align := trailingZeroBits(lower, bitLen)
+		for align > 0 {
+			blockEnd := new(big.Int).Add(lower, new(big.Int).Lsh(big.NewInt(1), uint(align)))
+			if blockEnd.Cmp(upper) <= 0 {
+				break
+			}
+			align--
+		}
+		ip := addressFromInt(new(big.Int).Set(lower), family).ToIP()
+		prefixLen := bitLen - align
+		blocks = append(blocks, &net.IPNet{IP: ip, Mask: net.CIDRMask(prefixLen, bitLen)})
+		lower.Add(lower, new(big.Int).Lsh(big.NewInt(1), uint(align)))
+	}
+	return blocks
+}
+
+// trailingZeroBits returns the number of trailing zero bits in v, i.e. how
+// large a power-of-two block v is aligned to, capped at max.
+func trailingZeroBits(v *big.Int, max int) int {
+	if v.Sign() == 0 {
+		return max
+	}
+	zeros := int(v.TrailingZeroBits())
+	if zeros > max {
+		return max
+	}
+	return zeros
+}
+
+// Coalesce merges a list of intervals into the minimum number of
+// non-overlapping, non-adjacent intervals that cover the same addresses.
+// It sorts by lower bound and sweeps once, so unlike checking CanJoin
+// against every interval seen so far it also catches transitive merges,
+// where two intervals only become adjacent once a third one bridges them.
+func Coalesce(intervals []*Interval) []*Interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sorted := make([]*Interval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].lower.IntValue.Cmp(sorted[j].lower.IntValue) < 0
+	})
+	var merged []*Interval
+	current := sorted[0]
+	for _, next := range sorted[1:] {
+		if CanJoin(current, next) {
+			current = Join(current, next)
+		} else {
+			merged = append(merged, current)
+			current = next
+		}
+	}
+	merged = append(merged, current)
+	return merged
+}
+
+func (n *Interval) String() string {
+	return fmt.Sprintf(
+		"%s - %s",
+		n.Lower(), n.Upper(),
+	)
+}
+
+// Copied from net package
+// Is p all zeros?
+func isZeros(p net.IP) bool {
+	for i := 0; i < len(p); i++ {
+		if p[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+func (n Interval)SubNets() []string {
+	subNets := make([]string, len(n.subNets))
+	for i,subnet := range n.subNets {
+		subNets[i] = subnet.String()
+	}
+	return subNets
+}
+
+*/
@@ -1,4 +1,4 @@
-package main
+package interval
 
 import (
 	"net"
@@ -105,6 +105,41 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewIPv6(t *testing.T) {
+	tests := []struct {
+		CIDR    string
+		Address string
+		Next    string
+	}{
+		{"2001:db8::/32", "2001:db8::", "2001:db9::"},
+		{"fc00::/8", "fc00::", "fd00::"},
+	}
+	for _, test := range tests {
+		n := NewInterval(test.CIDR)
+		if n == nil {
+			t.Fatalf("expecting %s to produce an interval", test.CIDR)
+		}
+		if n.Family() != FamilyV6 {
+			t.Errorf("expecting %s to be an IPv6 interval, got %s", test.CIDR, n.Family())
+		}
+		eIP := net.ParseIP(test.Address)
+		eNext := net.ParseIP(test.Next)
+		if !n.Lower().Equal(eIP) {
+			t.Errorf("expecting network IP for CIDR %s to be %s, got %s", test.CIDR, eIP, n.Lower())
+		}
+		if !n.Upper().Equal(eNext) {
+			t.Errorf("expecting next network IP for CIDR %s to be %s, got %s", test.CIDR, eNext, n.Upper())
+		}
+	}
+
+	// Test host address (/128)
+	CIDR := "2001:db8::1/128"
+	n := NewInterval(CIDR)
+	if n != nil {
+		t.Errorf("Expecting no network from /128 CIDR, but got %v", n)
+	}
+}
+
 func TestCanJoin(t *testing.T) {
 	tests := []struct {
 		A       string
@@ -118,6 +153,11 @@ func TestCanJoin(t *testing.T) {
 		{"42.0.0.0/24", "42.0.0.0/16", true},            // Inclusion - inverted
 		{"223.252.192.0/24", "223.252.194.0/24", false}, // A < B
 		{"10.0.0.0/8", "1.0.0.0/16", false},             // B < A
+		{"2001:db8::/32", "2001:db8::/32", true},        // Same subnet, IPv6
+		{"2001:db8::/32", "2001:db9::/32", true},        // Adjacent, IPv6
+		{"2001:db8::/32", "2001:db8::/16", true},        // Inclusion, IPv6
+		{"2001:db8::/32", "2001:dba::/32", false},       // A < B, IPv6
+		{"10.0.0.0/8", "2001:db8::/32", false},          // Mixed family never joins
 	}
 	for _, test := range tests {
 		a := NewInterval(test.A)
@@ -139,6 +179,104 @@ func TestCanJoin(t *testing.T) {
 	}
 }
 
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		CIDRs []string
+		Lower string
+		Upper string
+	}{
+		// B bridges A and C: A and C only become adjacent after B is merged in.
+		{
+			[]string{"10.0.0.0/24", "10.0.2.0/24", "10.0.1.0/24"},
+			"10.0.0.0", "10.0.3.0",
+		},
+		{
+			[]string{"192.168.0.0/24", "192.168.1.0/24"},
+			"192.168.0.0", "192.168.2.0",
+		},
+	}
+	for _, test := range tests {
+		var intervals []*Interval
+		for _, cidr := range test.CIDRs {
+			intervals = append(intervals, NewInterval(cidr))
+		}
+		merged := Coalesce(intervals)
+		if len(merged) != 1 {
+			t.Fatalf("expecting %v to coalesce into a single interval, got %d", test.CIDRs, len(merged))
+		}
+		if merged[0].Lower().String() != test.Lower || merged[0].Upper().String() != test.Upper {
+			t.Errorf("expecting coalesced %v to be %s - %s, got %s", test.CIDRs, test.Lower, test.Upper, merged[0].String())
+		}
+	}
+
+	// Non-adjacent intervals stay separate.
+	intervals := []*Interval{
+		NewInterval("10.0.0.0/24"),
+		NewInterval("10.0.5.0/24"),
+	}
+	merged := Coalesce(intervals)
+	if len(merged) != 2 {
+		t.Errorf("expecting non-adjacent intervals to stay separate, got %d", len(merged))
+	}
+
+	// Different families never coalesce into each other, even when adjacent
+	// in their respective address space.
+	mixed := []*Interval{
+		NewInterval("10.0.0.0/24"),
+		NewInterval("2001:db8::/32"),
+	}
+	merged = Coalesce(mixed)
+	if len(merged) != 2 {
+		t.Errorf("expecting mixed-family intervals to stay separate, got %d", len(merged))
+	}
+}
+
+func TestCIDRs(t *testing.T) {
+	tests := []struct {
+		CIDR     string
+		Expected []string
+	}{
+		{"10.0.0.0/24", []string{"10.0.0.0/24"}},
+		// 10.0.0.0 - 10.0.0.192 is not aligned to a single prefix.
+		{"10.0.0.0/25", []string{"10.0.0.0/25"}},
+		{"0.0.1.0/24", []string{"0.0.1.0/24"}},
+	}
+	for _, test := range tests {
+		n := NewInterval(test.CIDR)
+		if n == nil {
+			t.Fatalf("expecting %s to produce an interval", test.CIDR)
+		}
+		var got []string
+		for _, cidr := range n.CIDRs() {
+			got = append(got, cidr.String())
+		}
+		if len(got) != len(test.Expected) {
+			t.Fatalf("expecting %s to decompose into %v, got %v", test.CIDR, test.Expected, got)
+		}
+		for i := range got {
+			if got[i] != test.Expected[i] {
+				t.Errorf("expecting %s to decompose into %v, got %v", test.CIDR, test.Expected, got)
+			}
+		}
+	}
+
+	// A range that isn't a single aligned block should decompose into more
+	// than one CIDR.
+	a := NewInterval("10.0.0.0/25")
+	b := NewInterval("10.0.0.128/26")
+	merged := Join(a, b)
+	got := merged.CIDRs()
+	expected := []string{"10.0.0.0/25", "10.0.0.128/26"}
+	if len(got) != len(expected) {
+		t.Fatalf("expecting merged range to decompose into %v, got %v", expected, got)
+	}
+	for i, cidr := range got {
+		if cidr.String() != expected[i] {
+			t.Errorf("expecting block %d to be %s, got %s", i, expected[i], cidr.String())
+		}
+	}
+}
+
 func TestJoin(t *testing.T) {
 	tests := []struct {
 		A     string
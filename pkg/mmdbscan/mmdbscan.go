@@ -0,0 +1,76 @@
+// Package mmdbscan iterates the networks in a MaxmindDB, decodes each one
+// into a normalized Record and turns the ones a Selector matches into
+// interval.Interval values.
+package mmdbscan
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/weregoat/mmdb_intervals/pkg/interval"
+)
+
+// Scan iterates every network in db, decodes it into a normalized Record
+// and calls fn with the resulting Interval for each one whose family is
+// in families and that sel matches.
+func Scan(db *maxminddb.Reader, sel Selector, families map[interval.Family]bool, fn func(*interval.Interval)) error {
+	databaseType := db.Metadata.DatabaseType
+	networks := db.Networks()
+	for networks.Next() {
+		record, subnet, err := decodeRecord(databaseType, networks)
+		if err != nil {
+			return err
+		}
+		if !families[subnetFamily(subnet)] {
+			continue
+		}
+		if !sel.Match(record) {
+			continue
+		}
+		n := interval.NewInterval(subnet.String())
+		if n != nil {
+			fn(n)
+		}
+	}
+	return networks.Err()
+}
+
+// subnetFamily reports whether a subnet returned by the MMDB iterator is
+// IPv4 or IPv6.
+func subnetFamily(subnet *net.IPNet) interval.Family {
+	if subnet.IP.To4() != nil {
+		return interval.FamilyV4
+	}
+	return interval.FamilyV6
+}
+
+// decodeRecord decodes the network currently pointed at by networks into
+// whichever geoip2 record type matches the database, and returns it as a
+// normalized Record so Selectors can stay database-agnostic.
+func decodeRecord(databaseType string, networks *maxminddb.Networks) (Record, *net.IPNet, error) {
+	switch {
+	case strings.Contains(databaseType, "ASN"):
+		var rec geoip2.ASN
+		subnet, err := networks.Network(&rec)
+		return Record{ASN: rec.AutonomousSystemNumber}, subnet, err
+	case strings.Contains(databaseType, "City"):
+		var rec geoip2.City
+		subnet, err := networks.Network(&rec)
+		record := Record{
+			Country:   rec.Country.IsoCode,
+			Continent: rec.Continent.Code,
+			City:      rec.City.Names["en"],
+		}
+		if len(rec.Subdivisions) > 0 {
+			record.Subdivision = rec.Subdivisions[0].IsoCode
+		}
+		return record, subnet, err
+	default:
+		var rec geoip2.Country
+		subnet, err := networks.Network(&rec)
+		return Record{Country: rec.Country.IsoCode, Continent: rec.Continent.Code}, subnet, err
+	}
+}
@@ -0,0 +1,294 @@
+package mmdbscan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Record is a normalized view over whichever geoip2 record type the active
+// database decodes into, so Selectors don't need to care whether the
+// lookup came from a Country, City or ASN database.
+type Record struct {
+	Country     string
+	Continent   string
+	Subdivision string
+	City        string
+	ASN         uint
+}
+
+// Selector decides whether a Record matches a filter expression, such as
+// `country=DE`, `asn=15169`, or a boolean combination of several.
+type Selector interface {
+	Match(rec Record) bool
+}
+
+type countrySelector string
+
+func (s countrySelector) Match(rec Record) bool {
+	return strings.EqualFold(string(s), rec.Country)
+}
+
+type continentSelector string
+
+func (s continentSelector) Match(rec Record) bool {
+	return strings.EqualFold(string(s), rec.Continent)
+}
+
+type subdivisionSelector string
+
+func (s subdivisionSelector) Match(rec Record) bool {
+	return strings.EqualFold(string(s), rec.Subdivision)
+}
+
+type citySelector string
+
+func (s citySelector) Match(rec Record) bool {
+	return strings.EqualFold(string(s), rec.City)
+}
+
+type asnSelector uint
+
+func (s asnSelector) Match(rec Record) bool {
+	return uint(s) == rec.ASN
+}
+
+// regionSelector matches the "US/CA" and "DE/Berlin" shorthand: a country
+// plus either its subdivision or city name.
+type regionSelector struct {
+	country string
+	region  string
+}
+
+func (s regionSelector) Match(rec Record) bool {
+	if !strings.EqualFold(s.country, rec.Country) {
+		return false
+	}
+	return strings.EqualFold(s.region, rec.Subdivision) || strings.EqualFold(s.region, rec.City)
+}
+
+type notSelector struct {
+	inner Selector
+}
+
+func (s notSelector) Match(rec Record) bool {
+	return !s.inner.Match(rec)
+}
+
+type andSelector []Selector
+
+func (s andSelector) Match(rec Record) bool {
+	for _, sel := range s {
+		if !sel.Match(rec) {
+			return false
+		}
+	}
+	return true
+}
+
+type orSelector []Selector
+
+func (s orSelector) Match(rec Record) bool {
+	for _, sel := range s {
+		if sel.Match(rec) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSelector parses a boolean selector expression such as
+// `country=DE OR asn=15169 AND NOT country=US`. NOT binds tighter than
+// AND, which binds tighter than OR; parentheses can override that.
+func ParseSelector(expr string) (Selector, error) {
+	p := &selectorParser{tokens: tokenizeSelector(expr)}
+	sel, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token %q in selector %q", p.tokens[p.pos], expr)
+	}
+	return sel, nil
+}
+
+// FromCountries builds a Selector that matches any of the given ISO
+// country codes, i.e. the legacy behaviour before -select existed.
+func FromCountries(countries []string) Selector {
+	terms := make(orSelector, len(countries))
+	for i, country := range countries {
+		terms[i] = countrySelector(country)
+	}
+	return terms
+}
+
+func tokenizeSelector(expr string) []string {
+	expr = strings.ReplaceAll(expr, "(", " ( ")
+	expr = strings.ReplaceAll(expr, ")", " ) ")
+	return strings.Fields(expr)
+}
+
+type selectorParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *selectorParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *selectorParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *selectorParser) parseOr() (Selector, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := orSelector{left}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *selectorParser) parseAnd() (Selector, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	terms := andSelector{left}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *selectorParser) parseNot() (Selector, error) {
+	if strings.EqualFold(p.peek(), "NOT") {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notSelector{inner}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *selectorParser) parseAtom() (Selector, error) {
+	tok := p.peek()
+	if tok == "" {
+		return nil, fmt.Errorf("unexpected end of selector expression")
+	}
+	if tok == "(" {
+		p.next()
+		sel, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("expected closing ')' in selector")
+		}
+		p.next()
+		return sel, nil
+	}
+	p.next()
+	return parseSelectorTerm(tok)
+}
+
+// parseSelectorTerm parses a single leaf term: either `key=value` or one
+// of the bare shorthands (AS15169, EU, DE, US/CA, DE/Berlin). Continent
+// codes that collide with an assigned country code (AF, AS, NA, SA) are
+// not accepted bare; use `continent=` for those.
+func parseSelectorTerm(tok string) (Selector, error) {
+	if key, value, ok := strings.Cut(tok, "="); ok {
+		return newSelectorLeaf(key, value)
+	}
+	if n, ok := parseASN(tok); ok {
+		return asnSelector(n), nil
+	}
+	if isContinentCode(tok) {
+		return continentSelector(tok), nil
+	}
+	if country, region, ok := strings.Cut(tok, "/"); ok {
+		return regionSelector{country: country, region: region}, nil
+	}
+	return countrySelector(tok), nil
+}
+
+// isContinentCode reports whether tok is one of the MaxMind GeoIP2
+// continent codes, so a bare "EU" resolves to a continentSelector instead
+// of silently falling through to a countrySelector that can never match.
+// AF, AS, NA and SA are deliberately excluded even though they are valid
+// continent codes: they also collide with assigned ISO 3166-1 country
+// codes (Afghanistan, American Samoa, Namibia, Saudi Arabia), so treating
+// them as bare continent shorthand would silently reinterpret a country
+// selector the user almost certainly meant. Use `continent=AF` etc. for
+// those instead.
+func isContinentCode(tok string) bool {
+	switch strings.ToUpper(tok) {
+	case "AN", "EU", "OC":
+		return true
+	default:
+		return false
+	}
+}
+
+func newSelectorLeaf(key, value string) (Selector, error) {
+	switch strings.ToLower(key) {
+	case "country":
+		return countrySelector(value), nil
+	case "continent":
+		return continentSelector(value), nil
+	case "subdivision":
+		return subdivisionSelector(value), nil
+	case "city":
+		return citySelector(value), nil
+	case "region":
+		country, region, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("region selector %q must be COUNTRY/NAME", value)
+		}
+		return regionSelector{country: country, region: region}, nil
+	case "asn":
+		n, ok := parseASN(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid asn %q", value)
+		}
+		return asnSelector(n), nil
+	default:
+		return nil, fmt.Errorf("unknown selector key %q", key)
+	}
+}
+
+func parseASN(value string) (uint, bool) {
+	trimmed := strings.TrimPrefix(strings.ToUpper(value), "AS")
+	n, err := strconv.ParseUint(trimmed, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(n), true
+}
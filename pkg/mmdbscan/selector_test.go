@@ -0,0 +1,92 @@
+package mmdbscan
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		Expr    string
+		Match   Record
+		NoMatch Record
+	}{
+		{
+			"country=DE",
+			Record{Country: "DE"},
+			Record{Country: "US"},
+		},
+		{
+			"asn=15169",
+			Record{ASN: 15169},
+			Record{ASN: 12345},
+		},
+		{
+			"country=DE OR asn=15169",
+			Record{ASN: 15169, Country: "US"},
+			Record{Country: "FR", ASN: 1},
+		},
+		{
+			"country=DE OR asn=15169 AND NOT country=US",
+			Record{ASN: 15169, Country: "FR"},
+			Record{ASN: 15169, Country: "US"},
+		},
+	}
+	for _, test := range tests {
+		sel, err := ParseSelector(test.Expr)
+		if err != nil {
+			t.Fatalf("expecting %q to parse, got error: %v", test.Expr, err)
+		}
+		if !sel.Match(test.Match) {
+			t.Errorf("expecting %q to match %+v", test.Expr, test.Match)
+		}
+		if sel.Match(test.NoMatch) {
+			t.Errorf("expecting %q to not match %+v", test.Expr, test.NoMatch)
+		}
+	}
+}
+
+func TestParseSelectorRegionShorthand(t *testing.T) {
+	sel, err := ParseSelector("US/CA")
+	if err != nil {
+		t.Fatalf("expecting US/CA to parse, got error: %v", err)
+	}
+	if !sel.Match(Record{Country: "US", Subdivision: "CA"}) {
+		t.Errorf("expecting US/CA to match country US, subdivision CA")
+	}
+	if sel.Match(Record{Country: "US", Subdivision: "NY"}) {
+		t.Errorf("expecting US/CA to not match country US, subdivision NY")
+	}
+	if sel.Match(Record{Country: "FR", Subdivision: "CA"}) {
+		t.Errorf("expecting US/CA to require country US")
+	}
+}
+
+func TestParseSelectorContinentShorthand(t *testing.T) {
+	sel, err := ParseSelector("EU")
+	if err != nil {
+		t.Fatalf("expecting EU to parse, got error: %v", err)
+	}
+	if !sel.Match(Record{Continent: "EU"}) {
+		t.Errorf("expecting bare EU to match continent EU")
+	}
+	if sel.Match(Record{Country: "EU"}) {
+		t.Errorf("expecting bare EU to match continent, not country")
+	}
+}
+
+func TestParseSelectorContinentCountryCollision(t *testing.T) {
+	// AF, AS, NA and SA are continent codes that also collide with an
+	// assigned ISO 3166-1 country code, so the bare form must keep
+	// matching the country, not the continent.
+	tests := []string{"AF", "AS", "NA", "SA"}
+	for _, code := range tests {
+		sel, err := ParseSelector(code)
+		if err != nil {
+			t.Fatalf("expecting %s to parse, got error: %v", code, err)
+		}
+		if !sel.Match(Record{Country: code}) {
+			t.Errorf("expecting bare %s to match country %s", code, code)
+		}
+		if sel.Match(Record{Continent: code}) {
+			t.Errorf("expecting bare %s to not match continent %s", code, code)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package nftsync reconciles an nftables interval set with a wanted list
+// of interval.Interval values, instead of blindly re-adding elements on
+// every run.
+package nftsync
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/nftables"
+
+	"github.com/weregoat/mmdb_intervals/pkg/interval"
+)
+
+const batchSize = 1000
+
+// Logger receives progress messages; pass nil to discard them.
+type Logger func(string)
+
+func (l Logger) log(message string) {
+	if l != nil {
+		l(message)
+	}
+}
+
+// Reconcile updates the nftables set named setName in table tableName so
+// it matches wanted: it reads back what's already there, computes the
+// elements that need adding and removing, and applies both inside a
+// single Flush so the set is never left half-updated. With dryRun it only
+// prints the diff to stdout; removals are only issued when prune is set.
+func Reconcile(tableName, setName string, wanted []*interval.Interval, dryRun, prune bool, log Logger) error {
+	var set *nftables.Set
+	conn := &nftables.Conn{}
+	tables, err := conn.ListTables()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if strings.EqualFold(table.Name, tableName) {
+			set, err = conn.GetSetByName(table, setName)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if set == nil {
+		return fmt.Errorf(
+			"could not find a set named %+q in table %+q",
+			setName, tableName,
+		)
+	}
+
+	existingElements, err := conn.GetSetElements(set)
+	if err != nil {
+		return err
+	}
+	added, removed := diffIntervals(elementsToIntervals(existingElements), wanted)
+
+	if dryRun {
+		for _, i := range removed {
+			fmt.Printf("- %s\n", i.String())
+		}
+		for _, i := range added {
+			fmt.Printf("+ %s\n", i.String())
+		}
+		return nil
+	}
+
+	if prune {
+		if toDelete := intervalsToElements(removed); len(toDelete) > 0 {
+			log.log(fmt.Sprintf("Removing %d stale interval(s) from @%s", len(removed), set.Name))
+			if err := conn.SetDeleteElements(set, toDelete); err != nil {
+				return err
+			}
+		}
+	}
+
+	elements := intervalsToElements(added)
+	for start := 0; start < len(elements); start += batchSize {
+		end := start + batchSize
+		if end > len(elements) {
+			end = len(elements)
+		}
+		log.log(
+			fmt.Sprintf(
+				"Adding elements from %d to %d to @%s",
+				start,
+				end,
+				set.Name,
+			),
+		)
+		if err := conn.SetAddElements(set, elements[start:end]); err != nil {
+			return err
+		}
+	}
+	return conn.Flush()
+}
+
+// familyKey returns the nftables element key for ip, using the byte
+// length that matches its family (4 bytes for IPv4, 16 for IPv6).
+func familyKey(ip net.IP, family interval.Family) []byte {
+	if family == interval.FamilyV6 {
+		return ip.To16()
+	}
+	return ip.To4()
+}
+
+// intervalsToElements turns intervals into the alternating
+// start/interval-end nftables.SetElement pairs the kernel expects.
+func intervalsToElements(intervals []*interval.Interval) []nftables.SetElement {
+	var elements []nftables.SetElement
+	for _, i := range intervals {
+		if i != nil {
+			family := i.Family()
+			elements = append(elements,
+				nftables.SetElement{Key: familyKey(i.Lower(), family)},
+				nftables.SetElement{Key: familyKey(i.Upper(), family), IntervalEnd: true},
+			)
+		}
+	}
+	return elements
+}
+
+// elementsToIntervals reverses intervalsToElements, pairing each start
+// element read back from the kernel with its following interval-end.
+func elementsToIntervals(elements []nftables.SetElement) []*interval.Interval {
+	var intervals []*interval.Interval
+	var lower *interval.Address
+	for _, element := range elements {
+		address := interval.AddressFromBytes(element.Key)
+		if element.IntervalEnd {
+			if lower != nil {
+				intervals = append(intervals, interval.FromBounds(lower, address))
+				lower = nil
+			}
+			continue
+		}
+		lower = address
+	}
+	return intervals
+}
+
+// diffIntervals returns the intervals in wanted that aren't in existing
+// (added) and the intervals in existing that aren't in wanted (removed).
+func diffIntervals(existing, wanted []*interval.Interval) (added, removed []*interval.Interval) {
+	for _, w := range wanted {
+		if !containsInterval(existing, w) {
+			added = append(added, w)
+		}
+	}
+	for _, e := range existing {
+		if !containsInterval(wanted, e) {
+			removed = append(removed, e)
+		}
+	}
+	return added, removed
+}
+
+func containsInterval(intervals []*interval.Interval, target *interval.Interval) bool {
+	for _, i := range intervals {
+		if i.Lower().Equal(target.Lower()) && i.Upper().Equal(target.Upper()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package nftsync
+
+import (
+	"testing"
+
+	"github.com/weregoat/mmdb_intervals/pkg/interval"
+)
+
+func TestDiffIntervals(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Existing []string
+		Wanted   []string
+		Added    []string
+		Removed  []string
+	}{
+		{
+			"identical sets diff to nothing",
+			[]string{"10.0.0.0/24"},
+			[]string{"10.0.0.0/24"},
+			nil,
+			nil,
+		},
+		{
+			"new interval is added, none removed",
+			[]string{"10.0.0.0/24"},
+			[]string{"10.0.0.0/24", "10.0.1.0/24"},
+			[]string{"10.0.1.0/24"},
+			nil,
+		},
+		{
+			"stale interval is removed, none added",
+			[]string{"10.0.0.0/24", "10.0.1.0/24"},
+			[]string{"10.0.0.0/24"},
+			nil,
+			[]string{"10.0.1.0/24"},
+		},
+		{
+			"disjoint sets add and remove",
+			[]string{"10.0.0.0/24"},
+			[]string{"10.0.1.0/24"},
+			[]string{"10.0.1.0/24"},
+			[]string{"10.0.0.0/24"},
+		},
+	}
+	for _, test := range tests {
+		existing := toIntervals(test.Existing)
+		wanted := toIntervals(test.Wanted)
+		added, removed := diffIntervals(existing, wanted)
+		if !sameIntervals(added, toIntervals(test.Added)) {
+			t.Errorf("%s: expecting added %v, got %v", test.Name, test.Added, added)
+		}
+		if !sameIntervals(removed, toIntervals(test.Removed)) {
+			t.Errorf("%s: expecting removed %v, got %v", test.Name, test.Removed, removed)
+		}
+	}
+}
+
+func TestIntervalsToElementsRoundTrip(t *testing.T) {
+	tests := []struct {
+		CIDR string
+	}{
+		{"10.0.0.0/24"},
+		{"2001:db8::/32"},
+	}
+	for _, test := range tests {
+		want := interval.NewInterval(test.CIDR)
+		elements := intervalsToElements([]*interval.Interval{want})
+		if len(elements) != 2 {
+			t.Fatalf("%s: expecting 2 elements, got %d", test.CIDR, len(elements))
+		}
+		if elements[0].IntervalEnd {
+			t.Errorf("%s: expecting first element to start the interval", test.CIDR)
+		}
+		if !elements[1].IntervalEnd {
+			t.Errorf("%s: expecting second element to end the interval", test.CIDR)
+		}
+		got := elementsToIntervals(elements)
+		if len(got) != 1 {
+			t.Fatalf("%s: expecting round trip to produce 1 interval, got %d", test.CIDR, len(got))
+		}
+		if !got[0].Lower().Equal(want.Lower()) || !got[0].Upper().Equal(want.Upper()) {
+			t.Errorf("%s: expecting round trip to preserve %s, got %s", test.CIDR, want.String(), got[0].String())
+		}
+	}
+}
+
+func toIntervals(cidrs []string) []*interval.Interval {
+	var intervals []*interval.Interval
+	for _, cidr := range cidrs {
+		intervals = append(intervals, interval.NewInterval(cidr))
+	}
+	return intervals
+}
+
+func sameIntervals(got, want []*interval.Interval) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if !got[i].Lower().Equal(want[i].Lower()) || !got[i].Upper().Equal(want[i].Upper()) {
+			return false
+		}
+	}
+	return true
+}